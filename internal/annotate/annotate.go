@@ -0,0 +1,48 @@
+// Package annotate records GeneratedCodeInfo annotations that map a Go
+// identifier protc-gen-go renamed (e.g. User.ID, renamed from the
+// protoc-gen-go default User.Id) back to the `.proto` source location that
+// named it, so IDEs and code search can still jump from `User.ID` in Go to
+// `user_id` in the `.proto` file.
+package annotate
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Recorder wraps protogen.GeneratedFile.AnnotateSymbol so callers can
+// record a rename unconditionally and only check the `annotate_renames`
+// plugin option once, instead of guarding every call site.
+type Recorder struct {
+	enabled bool
+}
+
+// NewRecorder returns a Recorder that records annotations only if enabled
+// is true, mirroring the `annotate_renames=true` plugin option.
+func NewRecorder(enabled bool) *Recorder {
+	return &Recorder{enabled: enabled}
+}
+
+// Rename records that g is about to write the identifier symbol for loc,
+// which protc-gen-go renamed away from the name protoc-gen-go would
+// otherwise have used. symbol must already be in the form
+// GeneratedFile.AnnotateSymbol matches emitted identifiers against: a bare
+// name for a top-level declaration (an enum constant, say), or
+// "Parent.Member" for a struct field or method. AnnotateSymbol resolves
+// symbol against the declarations in g's content when that content is
+// produced, so Rename can be called after the identifier was written to g.
+//
+// The Semantic_SET value marks this as a setter/field-identity site rather
+// than a read, following the protogen convention introduced alongside the
+// Semantic field on Annotation.
+func (r *Recorder) Rename(g *protogen.GeneratedFile, symbol string, loc protogen.Location) {
+	if !r.enabled {
+		return
+	}
+
+	semantic := descriptorpb.GeneratedCodeInfo_Annotation_SET
+	g.AnnotateSymbol(symbol, protogen.Annotation{
+		Location: loc,
+		Semantic: &semantic,
+	})
+}
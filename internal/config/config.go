@@ -0,0 +1,138 @@
+// Package config lets field customizations be declared in a YAML or JSON
+// document instead of `.proto` comments, for consumers who cannot or will
+// not edit the `.proto` files they generate from.
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"protc/internal/desc"
+)
+
+// Precedence controls which source wins when both a config file and an
+// inline `@go.name=`/`@xxx.tag=` comment customize the same field.
+type Precedence string
+
+const (
+	// PrecedenceComments keeps whatever parseComments already produced and
+	// only fills in gaps the comments left unset. This is the default.
+	PrecedenceComments Precedence = "comments"
+
+	// PrecedenceConfig lets the config file override comment directives.
+	PrecedenceConfig Precedence = "config"
+)
+
+type FieldConfig struct {
+	GoName string            `yaml:"go_name" json:"go_name"`
+	Tags   map[string]string `yaml:"tags" json:"tags"`
+}
+
+type MessageConfig struct {
+	Fields map[string]*FieldConfig `yaml:"fields" json:"fields"`
+}
+
+type FileConfig struct {
+	Messages map[string]*MessageConfig `yaml:"messages" json:"messages"`
+}
+
+type Config struct {
+	Precedence Precedence             `yaml:"precedence" json:"precedence"`
+	Files      map[string]*FileConfig `yaml:"files" json:"files"`
+}
+
+// Load reads and parses the document at path. YAML is a superset of JSON, so
+// a single unmarshal call handles both `tags.yaml` and `tags.json`.
+func Load(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	cfg := &Config{Precedence: PrecedenceComments}
+	if err = yaml.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Merge applies the customizations declared for protoPath into fd, honoring
+// cfg.Precedence for fields the comment parser already customized. Nested
+// messages must be addressable both by their qualified name ("User.Address")
+// and by the flattened "Parent_Child" form desc.FileDescriptor.Models keys
+// its entries with, so Merge tries both. A message with no `.proto` comment
+// directives at all has no entry in fd.Models yet — exactly the third-party,
+// can't-touch-the-`.proto` case config exists for — so Merge creates one
+// rather than dropping the config entry on the floor.
+//
+// Merge only updates fd. Tags merged here reach the generated struct tags
+// through retag.Rewrite, but a go_name set or overridden here is only
+// recorded on the desc.Field: it does not rename the identifier in the
+// generated Go, because Parse has already applied comment renames to the
+// protogen.File before Merge runs.
+func (cfg *Config) Merge(protoPath string, fd *desc.FileDescriptor) {
+	file, ok := cfg.Files[protoPath]
+	if !ok {
+		return
+	}
+
+	for qualifiedName, msg := range file.Messages {
+		model, ok := fd.Models[qualifiedName]
+		if !ok {
+			model, ok = fd.Models[flatten(qualifiedName)]
+		}
+		if !ok {
+			model = &desc.Model{Name: flatten(qualifiedName), Fields: make(map[string]*desc.Field), Oneofs: make(map[string]*desc.Field)}
+			fd.Models[model.Name] = model
+		}
+
+		mergeMessage(cfg.Precedence, model, msg)
+	}
+}
+
+func mergeMessage(precedence Precedence, model *desc.Model, msg *MessageConfig) {
+	for name, fc := range msg.Fields {
+		field, exists := model.Fields[name]
+		if !exists {
+			field = &desc.Field{Name: name}
+			model.Fields[name] = field
+		}
+
+		if fc.GoName != "" && (field.GoName == "" || precedence == PrecedenceConfig) {
+			field.GoName = fc.GoName
+		}
+
+		for kind, value := range fc.Tags {
+			mergeTag(field, precedence, kind, value)
+		}
+	}
+}
+
+func mergeTag(field *desc.Field, precedence Precedence, kind, value string) {
+	for _, tag := range field.Tags {
+		if tag.Kind == kind {
+			if precedence == PrecedenceConfig {
+				tag.Value = value
+			}
+			return
+		}
+	}
+
+	field.Tags = append(field.Tags, &desc.Tag{Kind: kind, Value: value})
+}
+
+// flatten turns the qualified name "Parent.Child" into the "Parent_Child"
+// form protogen assigns as the nested message's Go identifier.
+func flatten(qualifiedName string) string {
+	out := []byte(qualifiedName)
+	for i, c := range out {
+		if c == '.' {
+			out[i] = '_'
+		}
+	}
+
+	return string(out)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"testing"
+
+	"protc/internal/desc"
+)
+
+func TestConfigMerge(t *testing.T) {
+	fd := &desc.FileDescriptor{
+		ProtoPath: "foo.proto",
+		Models: map[string]*desc.Model{
+			"User": {
+				Name: "User",
+				Fields: map[string]*desc.Field{
+					"Id": {Name: "Id", Tags: []*desc.Tag{{Kind: "gorm", Value: "primaryKey"}}},
+				},
+			},
+		},
+	}
+
+	cfg := &Config{
+		Precedence: PrecedenceComments,
+		Files: map[string]*FileConfig{
+			"foo.proto": {
+				Messages: map[string]*MessageConfig{
+					"User": {
+						Fields: map[string]*FieldConfig{
+							"Id":   {Tags: map[string]string{"gorm": "overridden", "validate": "required"}},
+							"Name": {GoName: "Name", Tags: map[string]string{"validate": "required"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cfg.Merge("foo.proto", fd)
+
+	model := fd.Models["User"]
+	id := model.Fields["Id"]
+	if got := tagValue(id.Tags, "gorm"); got != "primaryKey" {
+		t.Errorf("comment-declared gorm tag overwritten: got %q, want %q", got, "primaryKey")
+	}
+	if got := tagValue(id.Tags, "validate"); got != "required" {
+		t.Errorf("config-only validate tag missing: got %q", got)
+	}
+
+	name, ok := model.Fields["Name"]
+	if !ok {
+		t.Fatal("config-only field Name was not merged in")
+	}
+	if name.GoName != "Name" {
+		t.Errorf("GoName = %q, want %q", name.GoName, "Name")
+	}
+}
+
+func TestConfigMergePrecedenceConfig(t *testing.T) {
+	fd := &desc.FileDescriptor{
+		Models: map[string]*desc.Model{
+			"User": {
+				Name: "User",
+				Fields: map[string]*desc.Field{
+					"Id": {Name: "Id", Tags: []*desc.Tag{{Kind: "gorm", Value: "primaryKey"}}},
+				},
+			},
+		},
+	}
+
+	cfg := &Config{
+		Precedence: PrecedenceConfig,
+		Files: map[string]*FileConfig{
+			"foo.proto": {
+				Messages: map[string]*MessageConfig{
+					"User": {
+						Fields: map[string]*FieldConfig{
+							"Id": {Tags: map[string]string{"gorm": "overridden"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cfg.Merge("foo.proto", fd)
+
+	if got := tagValue(fd.Models["User"].Fields["Id"].Tags, "gorm"); got != "overridden" {
+		t.Errorf("config precedence did not win: got %q", got)
+	}
+}
+
+func TestConfigMergeCreatesModel(t *testing.T) {
+	fd := &desc.FileDescriptor{
+		ProtoPath: "foo.proto",
+		Models:    map[string]*desc.Model{},
+	}
+
+	cfg := &Config{
+		Precedence: PrecedenceComments,
+		Files: map[string]*FileConfig{
+			"foo.proto": {
+				Messages: map[string]*MessageConfig{
+					"User": {
+						Fields: map[string]*FieldConfig{
+							"Id": {GoName: "ID", Tags: map[string]string{"gorm": "primaryKey"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cfg.Merge("foo.proto", fd)
+
+	model, ok := fd.Models["User"]
+	if !ok {
+		t.Fatal("config-only message User was not added to fd.Models")
+	}
+
+	id, ok := model.Fields["Id"]
+	if !ok {
+		t.Fatal("config-only field Id was not merged in")
+	}
+	if id.GoName != "ID" {
+		t.Errorf("GoName = %q, want %q", id.GoName, "ID")
+	}
+	if got := tagValue(id.Tags, "gorm"); got != "primaryKey" {
+		t.Errorf("gorm tag = %q, want %q", got, "primaryKey")
+	}
+}
+
+func tagValue(tags []*desc.Tag, kind string) string {
+	for _, tag := range tags {
+		if tag.Kind == kind {
+			return tag.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,467 @@
+// Package desc holds the intermediate representation the plugin builds up
+// from a protogen.File before it is used to drive code generation and, later,
+// struct-tag rewriting. It is kept separate from package main so that other
+// internal packages (retag, config, ...) can depend on it without importing
+// a main package, which Go does not allow.
+package desc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+type Tag struct {
+	Kind  string
+	Value string
+}
+
+type Field struct {
+	// Name represents original field name generated by protoc-gen-go command
+	Name string
+
+	// GoName represents customized field name specified with comments
+	GoName string
+
+	// Tags represent customized field tags of the field, tag 'protobuf' will be omitted
+	Tags []*Tag
+
+	// Location is the `.proto` source location of the declaration this
+	// Field was parsed from, kept so a rename can be annotated back to
+	// where it came from; see internal/annotate.
+	Location protogen.Location
+}
+
+type Model struct {
+	Name   string
+	Fields map[string]*Field
+
+	// Oneofs holds customizations for the message's oneof wrapper
+	// interfaces, keyed the same way Fields is.
+	Oneofs map[string]*Field
+
+	// Embed is the type named by a `@go.embed=` directive on the message's
+	// leading comment, requesting that the generated struct embed it.
+	Embed string
+
+	// Iface is the type named by a `@go.iface=` directive on the message's
+	// leading comment, requesting that the generated struct satisfy it.
+	Iface string
+
+	// Models represent nested models
+	models map[string]*Model
+}
+
+type Enum struct {
+	Name string
+
+	// Values maps each enum value's customization by the GoName it was
+	// renamed to, matching the convention Model.Fields and Model.Oneofs
+	// use: the key is always the identifier actually emitted into the
+	// generated Go, not the pre-rename protoc-gen-go default.
+	Values map[string]*Field
+}
+
+type Service struct {
+	Name string
+
+	// Methods maps each method's customization by the GoName it was
+	// renamed to; see Enum.Values for why this is the post-rename name.
+	Methods map[string]*Field
+}
+
+type FileDescriptor struct {
+	ProtoPath string
+	GoPath    string
+	Models    map[string]*Model
+	Enums     map[string]*Enum
+	Services  map[string]*Service
+}
+
+// New returns a FileDescriptor with its maps ready for Parse to populate.
+func New(protoPath, goPath string) *FileDescriptor {
+	return &FileDescriptor{
+		ProtoPath: protoPath,
+		GoPath:    goPath,
+		Models:    make(map[string]*Model),
+		Enums:     make(map[string]*Enum),
+		Services:  make(map[string]*Service),
+	}
+}
+
+// Parse walks the messages, enums and services of file and populates
+// desc.Models, desc.Enums and desc.Services with the members that carry
+// customization directives.
+func (desc *FileDescriptor) Parse(file *protogen.File) (err error) {
+	path := string(file.Desc.Path())
+
+	for _, msg := range file.Messages {
+		model := &Model{Name: msg.GoIdent.GoName, Fields: make(map[string]*Field), Oneofs: make(map[string]*Field), models: make(map[string]*Model)}
+		if err = model.parse(path, msg); err != nil {
+			return
+		}
+
+		desc.add(model)
+	}
+
+	for _, enum := range file.Enums {
+		e := &Enum{Name: enum.GoIdent.GoName, Values: make(map[string]*Field)}
+		if err = e.parse(path, enum); err != nil {
+			return
+		}
+
+		if len(e.Values) > 0 {
+			desc.Enums[e.Name] = e
+		}
+	}
+
+	for _, svc := range file.Services {
+		s := &Service{Name: svc.GoName, Methods: make(map[string]*Field)}
+		if err = s.parse(path, svc); err != nil {
+			return
+		}
+
+		if len(s.Methods) > 0 {
+			desc.Services[s.Name] = s
+		}
+	}
+
+	return nil
+}
+
+func (desc *FileDescriptor) add(model *Model) {
+	if len(model.Fields) > 0 || len(model.Oneofs) > 0 || model.Embed != "" || model.Iface != "" {
+		desc.Models[model.Name] = model
+	}
+
+	for _, nested := range model.models {
+		desc.add(nested)
+	}
+}
+
+func (model *Model) parse(path string, msg *protogen.Message) (err error) {
+	if err = model.parseDirectives(&msg.Comments); err != nil {
+		return
+	}
+
+	for index := range msg.Fields {
+		field := &Field{Name: msg.Fields[index].GoName, Location: msg.Fields[index].Location}
+		if err = field.parse(path, model.Name, &msg.Fields[index].Comments); err != nil {
+			return
+		}
+
+		if field.GoName != "" {
+			msg.Fields[index].GoName = field.GoName
+			msg.Fields[index].GoIdent.GoName = model.Name + "_" + field.GoName
+		}
+
+		if len(field.Tags) > 0 || field.GoName != "" {
+			key := field.Name
+			if field.GoName != "" {
+				key = field.GoName
+			}
+			model.Fields[key] = field
+		}
+	}
+
+	for _, oneof := range msg.Oneofs {
+		if oneof.Desc.IsSynthetic() {
+			continue
+		}
+
+		field := &Field{Name: oneof.GoName, Location: oneof.Location}
+		if err = field.parse(path, model.Name, &oneof.Comments); err != nil {
+			return
+		}
+
+		if field.GoName != "" {
+			oneof.GoName = field.GoName
+			oneof.GoIdent.GoName = model.Name + "_" + field.GoName
+			model.Oneofs[field.GoName] = field
+		}
+	}
+
+	for _, nestedMessage := range msg.Messages {
+		nested := &Model{Name: string(nestedMessage.GoIdent.GoName), Fields: make(map[string]*Field), Oneofs: make(map[string]*Field), models: make(map[string]*Model)}
+		if err = nested.parse(path, nestedMessage); err != nil {
+			return
+		}
+
+		if len(nested.Fields) > 0 || len(nested.Oneofs) > 0 || nested.Embed != "" || nested.Iface != "" || len(nested.models) > 0 {
+			model.models[nested.Name] = nested
+		}
+	}
+
+	return
+}
+
+func (enum *Enum) parse(path string, e *protogen.Enum) (err error) {
+	for _, value := range e.Values {
+		field := &Field{Name: value.GoIdent.GoName, Location: value.Location}
+		if err = field.parse(path, enum.Name, &value.Comments); err != nil {
+			return
+		}
+
+		if field.GoName != "" {
+			value.GoIdent.GoName = field.GoName
+			enum.Values[field.GoName] = field
+		}
+	}
+
+	return
+}
+
+func (svc *Service) parse(path string, s *protogen.Service) (err error) {
+	for _, method := range s.Methods {
+		field := &Field{Name: method.GoName, Location: method.Location}
+		if err = field.parse(path, svc.Name, &method.Comments); err != nil {
+			return
+		}
+
+		if field.GoName != "" {
+			method.GoName = field.GoName
+			svc.Methods[field.GoName] = field
+		}
+	}
+
+	return
+}
+
+func (field *Field) parse(path, scope string, comments *protogen.CommentSet) (err error) {
+	if len(comments.LeadingDetached) == 0 && comments.Leading == "" && comments.Trailing == "" {
+		return
+	}
+
+	var replacement protogen.Comments
+	for index, detached := range comments.LeadingDetached {
+		if replacement, err = field.parseComments(path, scope, detached); err != nil {
+			return
+		}
+
+		comments.LeadingDetached[index] = replacement
+	}
+
+	if replacement, err = field.parseComments(path, scope, comments.Leading); err != nil {
+		return
+	}
+
+	comments.Leading = replacement
+
+	if replacement, err = field.parseComments(path, scope, comments.Trailing); err != nil {
+		return
+	}
+
+	comments.Trailing = replacement
+
+	return
+}
+
+// parseDirectives scans a message's leading comment for the message-level
+// `@go.embed=` and `@go.iface=` directives, setting model.Embed/model.Iface
+// and stripping the matched lines out of the emitted doc comment.
+func (model *Model) parseDirectives(comments *protogen.CommentSet) (err error) {
+	if len(comments.LeadingDetached) == 0 && comments.Leading == "" {
+		return
+	}
+
+	var replacement protogen.Comments
+	for index, detached := range comments.LeadingDetached {
+		if replacement, err = model.parseMessageComments(detached); err != nil {
+			return
+		}
+
+		comments.LeadingDetached[index] = replacement
+	}
+
+	replacement, err = model.parseMessageComments(comments.Leading)
+	comments.Leading = replacement
+
+	return
+}
+
+func (model *Model) parseMessageComments(comments protogen.Comments) (replacement protogen.Comments, err error) {
+	if comments == "" {
+		return
+	}
+
+	validate := regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(strings.NewReader(string(comments)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		pattern := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		switch {
+		case strings.HasPrefix(pattern, "@go.embed="):
+			value := pattern[len("@go.embed="):]
+			if value != "" && validate.MatchString(value) {
+				model.Embed = value
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "skip %s go embed directive, illegal value '%s'", model.Name, value)
+		case strings.HasPrefix(pattern, "@go.iface="):
+			value := pattern[len("@go.iface="):]
+			if value != "" && validate.MatchString(value) {
+				model.Iface = value
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "skip %s go iface directive, illegal value '%s'", model.Name, value)
+		}
+
+		buf.WriteString(line)
+	}
+
+	return protogen.Comments(buf.String()), nil
+}
+
+var (
+	tagDirective = regexp.MustCompile(`^@([a-z]+)\.tag=(.*)$`)
+	validateName = regexp.MustCompile(`[0-9a-zA-Z_]`)
+)
+
+func (field *Field) parseComments(path, scope string, comments protogen.Comments) (replacement protogen.Comments, err error) {
+	if comments == "" {
+		return
+	}
+
+	var buf bytes.Buffer
+
+	scanner := bufio.NewScanner(strings.NewReader(string(comments)))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		pattern := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+		if strings.HasPrefix(pattern, "@go.name=") {
+			name := pattern[9:]
+			if name != "" && validateName.MatchString(name) && unicode.IsUpper(rune(name[0])) {
+				field.GoName = name
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr, "%s: %s.%s: skip go name replacement, illegal value '%s'\n", path, scope, field.Name, name)
+		} else if matches := tagDirective.FindStringSubmatch(pattern); len(matches) == 3 {
+			kind, raw := matches[1], strings.TrimSpace(matches[2])
+
+			if kind == "go" {
+				unquoted, _ := unquote(raw)
+				tags, terr := decomposeTag(unquoted)
+				if terr != nil {
+					fmt.Fprintf(os.Stderr, "%s: %s.%s: skip @go.tag directive, %s\n", path, scope, field.Name, terr)
+				} else {
+					field.Tags = append(field.Tags, tags...)
+					continue
+				}
+			} else {
+				value, quoted := unquote(raw)
+				if !quoted {
+					value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+				}
+
+				if quoted || !strings.ContainsRune(value, ' ') {
+					field.Tags = append(field.Tags, &Tag{Kind: kind, Value: value})
+					continue
+				}
+
+				fmt.Fprintf(os.Stderr, "%s: %s.%s: skip commentary tag '%s' declaration, illegal value '%s'\n", path, scope, field.Name, kind, value)
+			}
+		}
+
+		buf.WriteString(line)
+	}
+
+	return protogen.Comments(buf.String()), nil
+}
+
+// unquote strips a single matching pair of leading/trailing quotes (' or ")
+// from raw, if present, and resolves the \" and \\ escape sequences inside.
+// It reports whether raw was actually quoted, so callers can tell an
+// explicitly quoted empty/space-bearing value from a bare token.
+func unquote(raw string) (string, bool) {
+	if len(raw) < 2 {
+		return raw, false
+	}
+
+	quote := raw[0]
+	if (quote != '\'' && quote != '"') || raw[len(raw)-1] != quote {
+		return raw, false
+	}
+
+	inner := raw[1 : len(raw)-1]
+	var b strings.Builder
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) && (inner[i+1] == '"' || inner[i+1] == '\\') {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+
+	return b.String(), true
+}
+
+// decomposeTag parses raw as the value of a reflect.StructTag-style literal
+// (e.g. `json:"foo,omitempty" validate:"required"`) and returns one Tag per
+// key, in declaration order. It reports a precise error on malformed input
+// instead of silently dropping keys, mirroring reflect.StructTag.Lookup's
+// grammar.
+func decomposeTag(raw string) ([]*Tag, error) {
+	var tags []*Tag
+
+	for raw != "" {
+		i := 0
+		for i < len(raw) && raw[i] == ' ' {
+			i++
+		}
+		raw = raw[i:]
+		if raw == "" {
+			break
+		}
+
+		i = 0
+		for i < len(raw) && raw[i] > ' ' && raw[i] != ':' && raw[i] != '"' && raw[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(raw) || raw[i] != ':' || raw[i+1] != '"' {
+			return nil, fmt.Errorf("malformed struct tag %q", raw)
+		}
+		kind := raw[:i]
+		raw = raw[i+1:]
+
+		i = 1
+		for i < len(raw) && raw[i] != '"' {
+			if raw[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(raw) {
+			return nil, fmt.Errorf("malformed struct tag: unterminated value for %q", kind)
+		}
+		quoted := raw[:i+1]
+		raw = raw[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("malformed struct tag value for %q: %w", kind, err)
+		}
+
+		tags = append(tags, &Tag{Kind: kind, Value: value})
+	}
+
+	return tags, nil
+}
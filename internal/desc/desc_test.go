@@ -0,0 +1,84 @@
+package desc
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func TestFieldParseCommentsTagGrammar(t *testing.T) {
+	cases := []struct {
+		name     string
+		comment  string
+		wantTags []*Tag
+	}{
+		{
+			name:    "bare token unchanged",
+			comment: `@gorm.tag=primaryKey`,
+			wantTags: []*Tag{
+				{Kind: "gorm", Value: "primaryKey"},
+			},
+		},
+		{
+			name:    "double-quoted value preserves spaces",
+			comment: `@validate.tag="required, min=1, max=10"`,
+			wantTags: []*Tag{
+				{Kind: "validate", Value: "required, min=1, max=10"},
+			},
+		},
+		{
+			name:    "go.tag decomposes multiple pairs",
+			comment: `@go.tag='json:"foo,omitempty" validate:"required"'`,
+			wantTags: []*Tag{
+				{Kind: "json", Value: "foo,omitempty"},
+				{Kind: "validate", Value: "required"},
+			},
+		},
+		{
+			name:    "escaped quote inside value",
+			comment: `@note.tag="say \"hi\""`,
+			wantTags: []*Tag{
+				{Kind: "note", Value: `say "hi"`},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			field := &Field{Name: "Id"}
+			if _, err := field.parseComments("foo.proto", "User", protogen.Comments(tc.comment)); err != nil {
+				t.Fatalf("parseComments returned error: %v", err)
+			}
+
+			if len(field.Tags) != len(tc.wantTags) {
+				t.Fatalf("got %d tags, want %d: %+v", len(field.Tags), len(tc.wantTags), field.Tags)
+			}
+			for i, want := range tc.wantTags {
+				if got := field.Tags[i]; got.Kind != want.Kind || got.Value != want.Value {
+					t.Errorf("tag[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldParseCommentsRejectsBareSpaces(t *testing.T) {
+	field := &Field{Name: "Id"}
+	if _, err := field.parseComments("foo.proto", "User", protogen.Comments(`@gorm.tag=primary key`)); err != nil {
+		t.Fatalf("parseComments returned error: %v", err)
+	}
+
+	if len(field.Tags) != 0 {
+		t.Fatalf("unquoted value containing a space should be rejected, got %+v", field.Tags)
+	}
+}
+
+func TestDecomposeTagMalformed(t *testing.T) {
+	if _, err := decomposeTag(`json:"unterminated`); err == nil {
+		t.Fatal("expected an error for an unterminated tag value")
+	}
+
+	if _, err := decomposeTag(`not-a-tag`); err == nil {
+		t.Fatal("expected an error for a malformed tag")
+	}
+}
@@ -0,0 +1,263 @@
+// Package retag patches the struct tags of already-generated Go source.
+//
+// protogen gives a plugin no hook to override the `protobuf:"..."` tag
+// string it writes for a field, so the `@xxx.tag=` comment directives that
+// desc.FileDescriptor.Parse collects have nowhere to land during normal code
+// generation. Rewrite closes that gap the same way protoc-gen-retag does:
+// it re-parses each generated file's source with go/parser after
+// protogen.Plugin has produced the CodeGeneratorResponse, finds the struct
+// and field declarations that correspond to the collected Models, merges in
+// the requested tags, and re-prints the file before it is written to stdout.
+// The same pass also applies a model's `@go.embed=`/`@go.iface=` directives,
+// since neither has anywhere else to take effect: Model.Embed prepends an
+// anonymous field of the named type to the struct, and Model.Iface emits an
+// Is<iface>() marker method on it.
+package retag
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"protc/internal/desc"
+)
+
+// Rewrite patches the single file in resp named filename, if its content
+// declares a struct type matching a Model in fd.Models, merging that
+// model's field tags into the emitted struct tag literals. fd must hold
+// only the customizations parsed from the `.proto` file filename was
+// generated from: resp.File can contain the output of many `.proto`
+// files in one invocation, and a FileDescriptor shared across all of them
+// would let one file's directives leak onto an identically-named struct
+// in another. It is a no-op if filename is absent from resp or carries no
+// content.
+func Rewrite(resp *pluginpb.CodeGeneratorResponse, filename string, fd *desc.FileDescriptor) error {
+	for _, file := range resp.File {
+		if file.GetName() != filename || file.Content == nil {
+			continue
+		}
+
+		content, err := rewriteFile(file.GetContent(), fd)
+		if err != nil {
+			return fmt.Errorf("retag: %s: %w", file.GetName(), err)
+		}
+
+		file.Content = &content
+		return nil
+	}
+
+	return nil
+}
+
+func rewriteFile(src string, fd *desc.FileDescriptor) (string, error) {
+	fset := token.NewFileSet()
+	astFile, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return "", err
+	}
+
+	var rewritten bool
+	var markers []ast.Decl
+
+	ast.Inspect(astFile, func(node ast.Node) bool {
+		typeSpec, ok := node.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
+			return true
+		}
+
+		// Nested messages are recorded by desc.add under their flattened
+		// Parent_Child name, which is exactly the Go type name protogen
+		// emits for them, so no extra lookup is needed here.
+		model, ok := fd.Models[typeSpec.Name.Name]
+		if !ok {
+			return true
+		}
+
+		if structType.Fields != nil {
+			for _, astField := range structType.Fields.List {
+				for _, name := range astField.Names {
+					field, ok := model.Fields[name.Name]
+					if !ok || len(field.Tags) == 0 {
+						continue
+					}
+
+					if astField.Tag == nil {
+						astField.Tag = &ast.BasicLit{Kind: token.STRING}
+					}
+
+					astField.Tag.Value = mergeTag(astField.Tag.Value, field.Tags)
+					rewritten = true
+				}
+			}
+		}
+
+		if model.Embed != "" {
+			if structType.Fields == nil {
+				structType.Fields = &ast.FieldList{}
+			}
+
+			if !hasEmbeddedField(structType, model.Embed) {
+				structType.Fields.List = append([]*ast.Field{{Type: ast.NewIdent(model.Embed)}}, structType.Fields.List...)
+				rewritten = true
+			}
+		}
+
+		if model.Iface != "" {
+			markers = append(markers, markerMethodDecl(typeSpec.Name.Name, model.Iface))
+			rewritten = true
+		}
+
+		return true
+	})
+
+	if len(markers) > 0 {
+		astFile.Decls = append(astFile.Decls, markers...)
+	}
+
+	if !rewritten {
+		return src, nil
+	}
+
+	var buf bytes.Buffer
+	if err = printer.Fprint(&buf, fset, astFile); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// hasEmbeddedField reports whether structType already embeds a field named
+// typeName, so Rewrite stays idempotent if it is ever asked to patch the
+// same struct twice.
+func hasEmbeddedField(structType *ast.StructType, typeName string) bool {
+	if structType.Fields == nil {
+		return false
+	}
+
+	for _, field := range structType.Fields.List {
+		if len(field.Names) != 0 {
+			continue
+		}
+
+		if ident, ok := field.Type.(*ast.Ident); ok && ident.Name == typeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markerMethodDecl builds the zero-value `func (*model) Is<iface>() {}`
+// requested by a message's `@go.iface=` directive. A target interface opts
+// into this convention by declaring exactly one method, Is<iface>(), the
+// same way protoc-gen-go itself seals a oneof wrapper interface with an
+// unexported isParent_Oneof() marker method.
+func markerMethodDecl(model, iface string) *ast.FuncDecl {
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{List: []*ast.Field{
+			{Type: &ast.StarExpr{X: ast.NewIdent(model)}},
+		}},
+		Name: ast.NewIdent("Is" + iface),
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{},
+	}
+}
+
+// mergeTag rewrites raw, a Go string literal including its surrounding
+// backticks or double quotes (or the empty string, for a freshly
+// synthesized tag), so that every tag in tags is present. The original protobuf tag, and any key
+// the caller did not ask to override, are preserved as-is.
+func mergeTag(raw string, tags []*desc.Tag) string {
+	unquoted := raw
+	switch {
+	case len(unquoted) >= 2 && unquoted[0] == '`':
+		unquoted = unquoted[1 : len(unquoted)-1]
+	default:
+		if s, err := strconv.Unquote(unquoted); err == nil {
+			unquoted = s
+		}
+	}
+
+	values, order := splitTag(unquoted)
+	for _, tag := range tags {
+		if _, exists := values[tag.Kind]; !exists {
+			order = append(order, tag.Kind)
+		}
+		values[tag.Kind] = tag.Value
+	}
+
+	var b strings.Builder
+	for index, kind := range order {
+		if index > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(kind)
+		b.WriteByte(':')
+		b.WriteString(strconv.Quote(values[kind]))
+	}
+
+	return "`" + b.String() + "`"
+}
+
+// splitTag decomposes a struct tag string into its key/value pairs,
+// preserving declaration order, which reflect.StructTag does not expose.
+func splitTag(tag string) (map[string]string, []string) {
+	values := make(map[string]string)
+	var order []string
+
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		name := tag[:i]
+		tag = tag[i+1:]
+
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			continue
+		}
+
+		values[name] = value
+		order = append(order, name)
+	}
+
+	return values, order
+}
@@ -0,0 +1,70 @@
+package retag
+
+import (
+	"strings"
+	"testing"
+
+	"protc/internal/desc"
+)
+
+func TestMergeTag(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		tags []*desc.Tag
+		want string
+	}{
+		{
+			name: "append new kind",
+			raw:  "`protobuf:\"varint,1,opt,name=id\"`",
+			tags: []*desc.Tag{{Kind: "gorm", Value: "primaryKey"}},
+			want: "`protobuf:\"varint,1,opt,name=id\" gorm:\"primaryKey\"`",
+		},
+		{
+			name: "override existing kind",
+			raw:  "`protobuf:\"bytes,2,opt,name=name\" validate:\"-\"`",
+			tags: []*desc.Tag{{Kind: "validate", Value: "required"}},
+			want: "`protobuf:\"bytes,2,opt,name=name\" validate:\"required\"`",
+		},
+		{
+			name: "synthesize onto an untagged field",
+			raw:  "",
+			tags: []*desc.Tag{{Kind: "json", Value: "id"}},
+			want: "`json:\"id\"`",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := mergeTag(tc.raw, tc.tags)
+			if got != tc.want {
+				t.Errorf("mergeTag(%q) = %q, want %q", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRewriteFileEmbedsAndMarksInterface(t *testing.T) {
+	const src = `package example
+
+type User struct {
+	Id string ` + "`protobuf:\"bytes,1,opt,name=id\"`" + `
+}
+`
+
+	fd := &desc.FileDescriptor{Models: map[string]*desc.Model{
+		"User": {Name: "User", Embed: "Audited", Iface: "Entity"},
+	}}
+
+	got, err := rewriteFile(src, fd)
+	if err != nil {
+		t.Fatalf("rewriteFile: %v", err)
+	}
+
+	if !strings.Contains(got, "Audited\n") {
+		t.Errorf("rewriteFile did not embed Audited:\n%s", got)
+	}
+	if !strings.Contains(got, "func (*User) IsEntity() {\n}") {
+		t.Errorf("rewriteFile did not emit the IsEntity marker method:\n%s", got)
+	}
+}
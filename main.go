@@ -0,0 +1,192 @@
+// Command protc-gen-go is a protoc plugin that wraps the stock
+// protoc-gen-go code generator with the customization machinery the rest
+// of this module builds: `@go.name=`/`@xxx.tag=` comment directives and an
+// optional config file all feed internal/desc.FileDescriptor, which
+// internal/retag then uses to patch the struct tags protoc-gen-go itself
+// has no hook to override.
+//
+// It cannot simply call protogen.Options.Run, because Run marshals the
+// plugin's CodeGeneratorResponse straight to stdout once the callback
+// returns, leaving nothing for internal/retag to rewrite. Instead it
+// replicates Run's request/response plumbing by hand, the same way
+// protoc-gen-retag wraps protoc-gen-go, so the response can be patched in
+// between.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"protc/internal/annotate"
+	"protc/internal/desc"
+	"protc/internal/retag"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("protc-gen-go: read request: %w", err)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err = proto.Unmarshal(input, req); err != nil {
+		return fmt.Errorf("protc-gen-go: unmarshal request: %w", err)
+	}
+
+	gen, err := protogen.Options{ParamFunc: flags.Set}.New(req)
+	if err != nil {
+		return fmt.Errorf("protc-gen-go: %w", err)
+	}
+
+	gen.SupportedFeatures = gengo.SupportedFeatures
+
+	resp, err := generate(gen)
+	if err != nil {
+		gen.Error(err)
+		resp = gen.Response()
+	}
+
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("protc-gen-go: marshal response: %w", err)
+	}
+
+	if _, err = os.Stdout.Write(out); err != nil {
+		return fmt.Errorf("protc-gen-go: write response: %w", err)
+	}
+
+	return nil
+}
+
+// generate runs protoc-gen-go's own generator for every requested file,
+// then applies protc's customizations on top of what it wrote: cfg, when
+// -config was passed, is merged into a FileDescriptor scoped to that one
+// `.proto` file's comments, retag.Rewrite patches struct tags from it, and
+// annotateRenames records where `annotate_renames=true` asked for a
+// GeneratedCodeInfo annotation on a renamed identifier. A FileDescriptor is
+// built per source file, not once for the whole run, so that two files
+// generated together which happen to declare an identically-named message
+// don't bleed customizations into each other.
+func generate(gen *protogen.Plugin) (*pluginpb.CodeGeneratorResponse, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := newAnnotateRecorder()
+
+	var scopes []struct {
+		filename string
+		fd       *desc.FileDescriptor
+	}
+
+	for _, file := range gen.Files {
+		if !file.Generate {
+			continue
+		}
+
+		path := string(file.Desc.Path())
+		fd := desc.New(path, string(file.GoImportPath))
+		if err = fd.Parse(file); err != nil {
+			return nil, err
+		}
+
+		if cfg != nil {
+			cfg.Merge(path, fd)
+		}
+
+		g := gengo.GenerateFile(gen, file)
+		annotateRenames(recorder, g, file, fd)
+
+		scopes = append(scopes, struct {
+			filename string
+			fd       *desc.FileDescriptor
+		}{filename: file.GeneratedFilenamePrefix + ".pb.go", fd: fd})
+	}
+
+	resp := gen.Response()
+	for _, scope := range scopes {
+		if err = retag.Rewrite(resp, scope.filename, scope.fd); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// annotateRenames walks every message, oneof, enum value and method in
+// file and records a rename annotation for each one fd says was given a
+// `@go.name=` customization, in the "Parent.Member"/bare-name form
+// AnnotateSymbol actually matches against emitted identifiers.
+func annotateRenames(recorder *annotate.Recorder, g *protogen.GeneratedFile, file *protogen.File, fd *desc.FileDescriptor) {
+	for _, msg := range file.Messages {
+		annotateMessage(recorder, g, fd, msg)
+	}
+
+	for _, enum := range file.Enums {
+		e, ok := fd.Enums[enum.GoIdent.GoName]
+		if !ok {
+			continue
+		}
+
+		for _, value := range enum.Values {
+			// Enum constants are top-level declarations, so unlike a
+			// struct field or method there is no "Parent." to qualify
+			// them with.
+			if field, ok := e.Values[value.GoIdent.GoName]; ok {
+				recorder.Rename(g, value.GoIdent.GoName, field.Location)
+			}
+		}
+	}
+
+	for _, svc := range file.Services {
+		s, ok := fd.Services[svc.GoName]
+		if !ok {
+			continue
+		}
+
+		// A renamed method is declared on both the <Service>Server and
+		// <Service>Client interfaces; annotate it against the server one,
+		// the canonical declaration site, since AnnotateSymbol has no way
+		// to record both for one rename.
+		server := svc.GoName + "Server"
+		for _, method := range svc.Methods {
+			if field, ok := s.Methods[method.GoName]; ok {
+				recorder.Rename(g, server+"."+method.GoName, field.Location)
+			}
+		}
+	}
+}
+
+func annotateMessage(recorder *annotate.Recorder, g *protogen.GeneratedFile, fd *desc.FileDescriptor, msg *protogen.Message) {
+	if model, ok := fd.Models[msg.GoIdent.GoName]; ok {
+		for _, pfield := range msg.Fields {
+			if field, ok := model.Fields[pfield.GoName]; ok && field.GoName != "" {
+				recorder.Rename(g, model.Name+"."+pfield.GoName, field.Location)
+			}
+		}
+
+		for _, oneof := range msg.Oneofs {
+			if field, ok := model.Oneofs[oneof.GoName]; ok {
+				recorder.Rename(g, model.Name+"."+oneof.GoName, field.Location)
+			}
+		}
+	}
+
+	for _, nested := range msg.Messages {
+		annotateMessage(recorder, g, fd, nested)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+
+	"protc/internal/annotate"
+	"protc/internal/config"
+)
+
+// flags holds the plugin options parsed out of `--protc-gen-go_opt=...`.
+var flags flag.FlagSet
+
+// configPath is set via `--protc-gen-go_opt=config=path/to/tags.yaml` and
+// points at a config.Config document that is merged into every
+// FileDescriptor this run produces, alongside whatever the `.proto`
+// comments already declared.
+var configPath = flags.String("config", "", "path to a YAML/JSON file of go_name/tag overrides, merged with comment directives")
+
+// annotateRenamesFlag is set via `--protc-gen-go_opt=annotate_renames=true` and
+// makes every renamed identifier emit a GeneratedCodeInfo annotation back
+// to its `.proto` source location, alongside the `.pb.go.meta` sidecar
+// protogen produces for any file that carries annotations.
+//
+// This is deliberately not named "annotate_code": protogen.Options.New
+// already special-cases that exact param name for its own, unrelated
+// annotate-every-symbol feature and never passes it through to ParamFunc,
+// so a plugin option of the same name can never actually be set.
+var annotateRenamesFlag = flags.Bool("annotate_renames", false, "emit GeneratedCodeInfo annotations for renamed identifiers")
+
+// loadConfig is a thin wrapper around config.Load that tolerates the common
+// case of no -config option having been passed at all.
+func loadConfig() (*config.Config, error) {
+	if *configPath == "" {
+		return nil, nil
+	}
+
+	return config.Load(*configPath)
+}
+
+// newAnnotateRecorder mirrors loadConfig for the annotate_renames option.
+func newAnnotateRecorder() *annotate.Recorder {
+	return annotate.NewRecorder(*annotateRenamesFlag)
+}